@@ -0,0 +1,86 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The gitbrute command brute-forces a git commit hash prefix.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/bradfitz/gitbrute"
+)
+
+var (
+	prefix   = flag.String("prefix", "", `Desired hex prefix of the resulting commit hash. Defaults to "bf" if no other match target (-suffix, -contains, -regex, -bits) is given.`)
+	suffix   = flag.String("suffix", "", "Desired hex suffix of the resulting commit hash.")
+	contains = flag.String("contains", "", "Hex substring the resulting commit hash must contain, at any position.")
+	regexFl  = flag.String("regex", "", "Regular expression the resulting commit hash's hex encoding must match.")
+	bitsFl   = flag.Int("bits", 0, "Number of leading zero bits required in the resulting commit hash, like a proof-of-work difficulty.")
+
+	force    = flag.Bool("force", false, "Re-run, even if current hash already matches -prefix. Other match targets always re-run.")
+	cpu      = flag.Int("cpus", runtime.NumCPU(), "Number of CPUs to use. Defaults to number of processors.")
+	hashAlgo = flag.String("hash", "", "Hash algorithm to use: sha1 or sha256. Defaults to the repository's extensions.objectFormat, or sha1 if unset.")
+
+	nonce         = flag.Bool("nonce", false, "Brute-force a Nonce trailer in the commit message, in addition to the author/committer timestamps. Needed to hit prefixes longer than the timestamps alone can reach.")
+	nonceOnly     = flag.Bool("nonce-only", false, "Brute-force only the Nonce trailer, leaving the author/committer timestamps untouched. Implies -nonce.")
+	nonceAlphabet = flag.String("nonce-alphabet", gitbrute.DefaultNonceAlphabet, "Alphabet to draw Nonce trailer characters from.")
+	nonceLen      = flag.Int("nonce-len", gitbrute.DefaultNonceLen, "Width, in characters, of the Nonce trailer's value.")
+)
+
+func main() {
+	flag.Parse()
+	otherTarget := *suffix != "" || *contains != "" || *regexFl != "" || *bitsFl != 0
+	if *prefix == "" && !otherTarget {
+		*prefix = "bf"
+	}
+
+	r, err := gitbrute.Open(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !*force && !otherTarget {
+		head, err := r.HeadHash()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.HasPrefix(head.String(), strings.ToLower(*prefix)) {
+			return
+		}
+	}
+
+	newHash, err := r.BruteForceHead(gitbrute.Options{
+		Prefix:        *prefix,
+		Suffix:        *suffix,
+		Contains:      *contains,
+		Regex:         *regexFl,
+		Bits:          *bitsFl,
+		HashAlgo:      *hashAlgo,
+		CPU:           *cpu,
+		Nonce:         *nonce || *nonceOnly,
+		NonceOnly:     *nonceOnly,
+		NonceAlphabet: *nonceAlphabet,
+		NonceLen:      *nonceLen,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(newHash)
+}
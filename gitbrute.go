@@ -14,75 +14,281 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// The gitbrute command brute-forces a git commit hash prefix.
-package main
+// Package gitbrute brute-forces a git commit's hash to match a desired
+// target (a prefix, suffix, substring, regexp, or leading-zero-bit
+// count), by varying its author/committer timestamps and/or a Nonce
+// trailer in its message, and re-forging the commit object in place.
+package gitbrute
 
 import (
-	"bytes"
 	"crypto/sha1"
-	"flag"
+	"crypto/sha256"
+	"encoding"
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
+	"hash"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
 )
 
-var (
-	prefix = flag.String("prefix", "bf", "Desired prefix")
-	force  = flag.Bool("force", false, "Re-run, even if current hash matches prefix")
-	cpu    = flag.Int("cpus", runtime.NumCPU(), "Number of CPUs to use. Defaults to number of processors.")
-)
+// Options controls a brute-force run.
+type Options struct {
+	// Prefix is the desired hex prefix of the commit hash, matched
+	// case-insensitively.
+	// Exactly one of Prefix, Suffix, Contains, Regex, and Bits must
+	// be set.
+	Prefix string
+
+	// Suffix, if set, requires the commit hash's hex encoding to end
+	// with this string.
+	Suffix string
+
+	// Contains, if set, requires the commit hash's hex encoding to
+	// contain this string anywhere.
+	Contains string
+
+	// Regex, if set, requires the commit hash's hex encoding to
+	// match this regular expression.
+	Regex string
+
+	// Bits, if set, requires at least this many leading zero bits in
+	// the raw (non-hex) digest, like a proof-of-work difficulty.
+	Bits int
+
+	// HashAlgo selects the hash algorithm to brute-force against:
+	// "sha1" or "sha256". If empty, it's taken from the repository's
+	// extensions.objectFormat, defaulting to "sha1".
+	HashAlgo string
+
+	// CPU is the number of workers to run. Zero means
+	// runtime.NumCPU().
+	CPU int
+
+	// Nonce enables brute-forcing a "Nonce: <value>" trailer in the
+	// commit message, in addition to (or, if NonceOnly, instead of)
+	// the author/committer timestamps. This unlocks arbitrarily long
+	// prefixes, which the ~million-odd timestamp combinations near
+	// the real commit time can't reach.
+	Nonce bool
+
+	// NonceOnly, if set, brute-forces only the nonce, leaving the
+	// author/committer timestamps untouched. It implies Nonce, so
+	// setting NonceOnly alone is sufficient.
+	NonceOnly bool
+
+	// NonceAlphabet is the set of characters used for the nonce. If
+	// empty, DefaultNonceAlphabet is used.
+	NonceAlphabet string
+
+	// NonceLen is the fixed width of the nonce value. If zero,
+	// DefaultNonceLen is used.
+	NonceLen int
+}
+
+func (o Options) cpu() int {
+	if o.CPU > 0 {
+		return o.CPU
+	}
+	return runtime.NumCPU()
+}
+
+// buildMatcher resolves opts' match target (Prefix, Suffix, Contains,
+// Regex, or Bits — exactly one must be set) into a Matcher, validating
+// it against hexWidth, the hex-encoded digest width of the hash
+// algorithm in use.
+func buildMatcher(opts Options, hexWidth int) (Matcher, error) {
+	type target struct {
+		name string
+		set  bool
+	}
+	targets := []target{
+		{"Prefix", opts.Prefix != ""},
+		{"Suffix", opts.Suffix != ""},
+		{"Contains", opts.Contains != ""},
+		{"Regex", opts.Regex != ""},
+		{"Bits", opts.Bits != 0},
+	}
+	var set []string
+	for _, t := range targets {
+		if t.set {
+			set = append(set, t.name)
+		}
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("gitbrute: no match target set (one of Prefix, Suffix, Contains, Regex, Bits is required)")
+	}
+	if len(set) > 1 {
+		return nil, fmt.Errorf("gitbrute: at most one match target may be set; got %v", set)
+	}
 
-func main() {
-	flag.Parse()
-	runtime.GOMAXPROCS(*cpu)
-	if _, err := strconv.ParseInt(*prefix, 16, 64); err != nil {
-		log.Fatalf("Prefix %q isn't hex.", *prefix)
+	validateHex := func(name, s string) error {
+		if !validHexRx.MatchString(s) {
+			return fmt.Errorf("gitbrute: %s %q isn't hex", name, s)
+		}
+		if len(s) > hexWidth {
+			return fmt.Errorf("gitbrute: %s %q is longer than the %d hex digits produced by the hash in use", name, s, hexWidth)
+		}
+		return nil
 	}
 
-	hash := curHash()
-	if strings.HasPrefix(hash, *prefix) && !*force {
-		return
+	switch {
+	case opts.Prefix != "":
+		if err := validateHex("prefix", opts.Prefix); err != nil {
+			return nil, err
+		}
+		return PrefixMatcher(opts.Prefix), nil
+	case opts.Suffix != "":
+		if err := validateHex("suffix", opts.Suffix); err != nil {
+			return nil, err
+		}
+		return SuffixMatcher(opts.Suffix), nil
+	case opts.Contains != "":
+		if err := validateHex("contains", opts.Contains); err != nil {
+			return nil, err
+		}
+		return ContainsMatcher(opts.Contains), nil
+	case opts.Regex != "":
+		rx, err := regexp.Compile(opts.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("gitbrute: invalid regex %q: %w", opts.Regex, err)
+		}
+		return RegexMatcher(rx), nil
+	default: // opts.Bits != 0
+		if opts.Bits < 0 || opts.Bits > hexWidth*4 {
+			return nil, fmt.Errorf("gitbrute: bits %d out of range [0, %d]", opts.Bits, hexWidth*4)
+		}
+		return BitsMatcher(opts.Bits), nil
 	}
+}
 
-	obj, err := exec.Command("git", "cat-file", "-p", hash).Output()
+// hashFunc returns the hash constructor and hex-encoded digest width
+// to brute-force against, given the resolved algorithm name ("" or
+// "sha1" for SHA-1, "sha256" for SHA-256).
+func hashFunc(algo string) (newHash func() hash.Hash, hexWidth int, err error) {
+	switch algo {
+	case "", "sha1":
+		return sha1.New, sha1.Size * 2, nil
+	case "sha256":
+		return sha256.New, sha256.Size * 2, nil
+	default:
+		return nil, 0, fmt.Errorf("gitbrute: unknown hash algorithm %q", algo)
+	}
+}
+
+// BruteForceHead brute-forces the repository's HEAD commit to have a
+// hash matching opts' target (whichever one of Prefix, Suffix,
+// Contains, Regex, or Bits is set), re-forging and amending it in place. It
+// returns the new commit's hex hash.
+//
+// If HEAD was signed, or the repository has commit.gpgsign set,
+// BruteForceHead re-signs the re-forged commit with the configured
+// signing key rather than silently dropping the signature — note
+// that this means the final commit's hash no longer matches opts'
+// target, since the signature covers the matched bytes too.
+func (r *Repo) BruteForceHead(opts Options) (string, error) {
+	algo := opts.HashAlgo
+	if algo == "" {
+		algo = r.ObjectFormat()
+	}
+	newHash, hexWidth, err := hashFunc(algo)
+	if err != nil {
+		return "", err
+	}
+	matcher, err := buildMatcher(opts, hexWidth)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	i := bytes.Index(obj, []byte("\n\n"))
-	if i < 0 {
-		log.Fatalf("No \\n\\n found in %q", obj)
+
+	_, obj, err := r.HeadCommitObject()
+	if err != nil {
+		return "", err
+	}
+	obj, hadSig := stripGPGSig(obj)
+	signAfter, err := r.needsSignature(hadSig)
+	if err != nil {
+		return "", err
+	}
+
+	// NonceOnly implies brute-forcing the nonce even if the caller
+	// didn't also set Nonce: without it, nothing would ever vary the
+	// commit object's bytes and the search would never converge.
+	useNonce := opts.Nonce || opts.NonceOnly
+
+	alphabet := opts.NonceAlphabet
+	if alphabet == "" {
+		alphabet = DefaultNonceAlphabet
+	}
+	nonceLen := opts.NonceLen
+	if nonceLen <= 0 {
+		nonceLen = DefaultNonceLen
+	}
+	if useNonce {
+		obj, _ = injectNonceTrailer(obj, alphabet, nonceLen)
+	}
+
+	mode := exploreDates
+	switch {
+	case useNonce && opts.NonceOnly:
+		mode = exploreNonceOnly
+	case useNonce:
+		mode = exploreDatesAndNonce
 	}
-	msg := obj[i+2:]
 
 	possibilities := make(chan try, 512)
-	go explore(possibilities)
+	go explore(possibilities, mode)
 
 	winner := make(chan solution)
 	done := make(chan struct{})
 
-	for i := 0; i < *cpu; i++ {
-		go bruteForce(obj, winner, possibilities, done)
+	cpu := opts.cpu()
+	for i := 0; i < cpu; i++ {
+		go bruteForce(newHash, obj, matcher, useNonce, opts.NonceOnly, alphabet, winner, possibilities, done)
 	}
-
 	w := <-winner
 	close(done)
 
-	cmd := exec.Command("git", "commit", "--allow-empty", "--amend", "--date="+w.author.String(), "--file=-")
-	cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE="+w.committer.String())
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = bytes.NewReader(msg)
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("amend: %v", err)
+	content := obj
+	if !opts.NonceOnly {
+		content = rewriteDates(content, w.author, w.committer)
+	}
+	if useNonce {
+		content = rewriteNonce(content, w.nonce)
 	}
+
+	if signAfter {
+		// Signing covers the whole content, including the matched
+		// bytes, so the resulting commit's hash will no longer match
+		// opts' target — there's no way around that and still ship a
+		// valid signature. We still do it, rather than silently
+		// amending to an unsigned (or staler-signed) commit.
+		content, err = r.sign(content)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	newCommitHash, err := r.Amend(content)
+	if err != nil {
+		return "", err
+	}
+	return newCommitHash.String(), nil
+}
+
+// rewriteDates returns a copy of the commit object content obj with
+// its author and committer dates replaced by author and committer.
+func rewriteDates(obj []byte, author, committer date) []byte {
+	out := append([]byte(nil), obj...)
+	_, adatei := getDate(out, authorDateRx)
+	_, cdatei := getDate(out, committerDateRx)
+	strconv.AppendInt(out[:adatei], author.n, 10)
+	strconv.AppendInt(out[:cdatei], committer.n, 10)
+	return out
 }
 
 type solution struct {
 	author, committer date
+	nonce             string // the winning Nonce trailer value, if nonce brute-forcing was enabled
 }
 
 var (
@@ -90,46 +296,112 @@ var (
 	committerDateRx = regexp.MustCompile(`(?m)^committer.+> (.+)`)
 )
 
-func bruteForce(obj []byte, winner chan<- solution, possibilities <-chan try, done <-chan struct{}) {
+func bruteForce(newHash func() hash.Hash, obj []byte, matcher Matcher, useNonce, nonceOnly bool, alphabet string, winner chan<- solution, possibilities <-chan try, done <-chan struct{}) {
 	// blob is the blob to mutate in-place repeatedly while testing
 	// whether we have a match.
 	blob := []byte(fmt.Sprintf("commit %d\x00%s", len(obj), obj))
 	authorDate, adatei := getDate(blob, authorDateRx)
 	commitDate, cdatei := getDate(blob, committerDateRx)
 
-	s1 := sha1.New()
-	wantHexPrefix := []byte(strings.ToLower(*prefix))
-	hexBuf := make([]byte, 0, sha1.Size*2)
+	var nonceOffset, nonceWidth int
+	if useNonce {
+		var ok bool
+		nonceOffset, nonceWidth, ok = findNonceTrailer(blob)
+		if !ok {
+			panic("gitbrute: bruteForce: no Nonce trailer found")
+		}
+	}
+
+	h := newHash()
+	sumBuf := make([]byte, 0, h.Size()*2)
+
+	// The bytes of blob before the earliest byte actually mutated by
+	// a try never change across tries, so precompute the hash state
+	// after absorbing those whole blocks once, up front, instead of
+	// rehashing them on every try. Only consider the offsets this
+	// worker actually varies: in NonceOnly mode the dates are fixed,
+	// so the nonce trailer (near the end of the message) is the only
+	// mutable byte range, and using adatei/cdatei here would throw
+	// away nearly all of the speedup.
+	var mutableStart int
+	haveMutableStart := false
+	consider := func(i int) {
+		if !haveMutableStart || i < mutableStart {
+			mutableStart = i
+			haveMutableStart = true
+		}
+	}
+	if !nonceOnly {
+		consider(adatei)
+		consider(cdatei)
+	}
+	if useNonce {
+		consider(nonceOffset)
+	}
+	snapshot, tailStart, useMidstate := snapshotPrefix(h, blob, mutableStart)
+	bu, _ := h.(encoding.BinaryUnmarshaler)
 
 	for t := range possibilities {
 		select {
 		case <-done:
 			return
 		default:
-			ad := date{authorDate.n - int64(t.authorBehind), authorDate.tz}
-			cd := date{commitDate.n - int64(t.commitBehind), commitDate.tz}
-			strconv.AppendInt(blob[:adatei], ad.n, 10)
-			strconv.AppendInt(blob[:cdatei], cd.n, 10)
-			s1.Reset()
-			s1.Write(blob)
-			if !bytes.HasPrefix(hexInPlace(s1.Sum(hexBuf[:0])), wantHexPrefix) {
+			ad, cd := authorDate, commitDate
+			if !nonceOnly {
+				ad = date{authorDate.n - int64(t.authorBehind), authorDate.tz}
+				cd = date{commitDate.n - int64(t.commitBehind), commitDate.tz}
+				strconv.AppendInt(blob[:adatei], ad.n, 10)
+				strconv.AppendInt(blob[:cdatei], cd.n, 10)
+			}
+			var nonce string
+			if useNonce {
+				nonce = nonceString(alphabet, nonceWidth, t.nonce)
+				copy(blob[nonceOffset:nonceOffset+nonceWidth], nonce)
+			}
+			if useMidstate {
+				bu.UnmarshalBinary(snapshot)
+				h.Write(blob[tailStart:])
+			} else {
+				h.Reset()
+				h.Write(blob)
+			}
+			if !matcher.Match(h.Sum(sumBuf[:0])) {
 				continue
 			}
 
-			winner <- solution{ad, cd}
+			winner <- solution{ad, cd, nonce}
 			return
 		}
 	}
 }
 
-// try is a pair of seconds behind now to brute force, looking for a
-// matching commit.
+// try is a candidate to brute force, looking for a matching commit:
+// commitBehind and authorBehind are seconds behind now, and nonce is
+// an index into the Nonce trailer's keyspace, per exploreMode.
 type try struct {
 	commitBehind int
 	authorBehind int
+	nonce        uint64
 }
 
-// explore yields the sequence:
+// exploreMode selects what explore varies.
+type exploreMode int
+
+const (
+	// exploreDates varies only commitBehind/authorBehind, as before
+	// nonce support was added.
+	exploreDates exploreMode = iota
+	// exploreDatesAndNonce varies commitBehind/authorBehind and pairs
+	// each with the next nonce in sequence.
+	exploreDatesAndNonce
+	// exploreNonceOnly leaves the dates alone and only increments
+	// the nonce.
+	exploreNonceOnly
+)
+
+// explore yields try values according to mode. In exploreDates and
+// exploreDatesAndNonce mode, the (commitBehind, authorBehind) pairs
+// follow the sequence:
 //     (0, 0)
 //
 //     (0, 1)
@@ -143,13 +415,27 @@ type try struct {
 //     (2, 2)
 //
 //     ...
-func explore(c chan<- try) {
+// In exploreNonceOnly mode, only nonce counts up from 0.
+func explore(c chan<- try, mode exploreMode) {
+	if mode == exploreNonceOnly {
+		for n := uint64(0); ; n++ {
+			c <- try{nonce: n}
+		}
+	}
+	var n uint64
+	next := func(t try) {
+		if mode == exploreDatesAndNonce {
+			t.nonce = n
+			n++
+		}
+		c <- t
+	}
 	for max := 0; ; max++ {
 		for i := 0; i <= max-1; i++ {
-			c <- try{i, max}
+			next(try{commitBehind: i, authorBehind: max})
 		}
 		for j := 0; j <= max; j++ {
-			c <- try{max, j}
+			next(try{commitBehind: max, authorBehind: j})
 		}
 	}
 }
@@ -168,32 +454,20 @@ func (d date) String() string { return fmt.Sprintf("%d %s", d.n, d.tz) }
 func getDate(h []byte, rx *regexp.Regexp) (d date, idx int) {
 	m := rx.FindSubmatchIndex(h)
 	if m == nil {
-		log.Fatalf("Failed to match %s in %q", rx, h)
+		panic(fmt.Sprintf("gitbrute: failed to match %s in %q", rx, h))
 	}
 	v := string(h[m[2]:m[3]])
 	space := strings.Index(v, " ")
 	if space < 0 {
-		log.Fatalf("unexpected date %q", v)
+		panic(fmt.Sprintf("gitbrute: unexpected date %q", v))
 	}
 	n, err := strconv.ParseInt(v[:space], 10, 64)
 	if err != nil {
-		log.Fatalf("unexpected date %q", v)
+		panic(fmt.Sprintf("gitbrute: unexpected date %q", v))
 	}
 	return date{n, v[space+1:]}, m[2]
 }
 
-func curHash() string {
-	all, err := exec.Command("git", "rev-parse", "HEAD").Output()
-	if err != nil {
-		log.Fatal(err)
-	}
-	h := string(all)
-	if i := strings.Index(h, "\n"); i > 0 {
-		h = h[:i]
-	}
-	return h
-}
-
 // hexInPlace takes a slice of binary data and returns the same slice with double
 // its length, hex-ified in-place.
 func hexInPlace(v []byte) []byte {
@@ -0,0 +1,108 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestHashFunc(t *testing.T) {
+	tests := []struct {
+		algo        string
+		wantHexSize int
+		wantErr     bool
+	}{
+		{"", sha1.Size * 2, false},
+		{"sha1", sha1.Size * 2, false},
+		{"sha256", sha256.Size * 2, false},
+		{"md5", 0, true},
+	}
+	for _, tt := range tests {
+		newHash, hexWidth, err := hashFunc(tt.algo)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("hashFunc(%q) error = %v; wantErr %v", tt.algo, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if hexWidth != tt.wantHexSize {
+			t.Errorf("hashFunc(%q) hexWidth = %d; want %d", tt.algo, hexWidth, tt.wantHexSize)
+		}
+		if newHash() == nil {
+			t.Errorf("hashFunc(%q) returned a nil hash constructor", tt.algo)
+		}
+	}
+}
+
+func TestBuildMatcherTargetValidation(t *testing.T) {
+	const hexWidth = sha1.Size * 2
+
+	if _, err := buildMatcher(Options{}, hexWidth); err == nil {
+		t.Error("expected an error with no match target set")
+	}
+	if _, err := buildMatcher(Options{Prefix: "bf", Suffix: "ab"}, hexWidth); err == nil {
+		t.Error("expected an error with multiple match targets set")
+	}
+}
+
+func TestBuildMatcherHexValidation(t *testing.T) {
+	const hexWidth = 4
+
+	if _, err := buildMatcher(Options{Prefix: "zz"}, hexWidth); err == nil {
+		t.Error("expected an error for a non-hex Prefix")
+	}
+	if _, err := buildMatcher(Options{Prefix: "abcde"}, hexWidth); err == nil {
+		t.Error("expected an error for a Prefix longer than hexWidth")
+	}
+	if _, err := buildMatcher(Options{Suffix: "abcde"}, hexWidth); err == nil {
+		t.Error("expected an error for a Suffix longer than hexWidth")
+	}
+	if _, err := buildMatcher(Options{Contains: "abcde"}, hexWidth); err == nil {
+		t.Error("expected an error for a Contains longer than hexWidth")
+	}
+	if _, err := buildMatcher(Options{Regex: "("}, hexWidth); err == nil {
+		t.Error("expected an error for an invalid Regex")
+	}
+}
+
+func TestBuildMatcherBitsRange(t *testing.T) {
+	const hexWidth = sha1.Size * 2 // hashFunc's hexWidth for sha1
+
+	if _, err := buildMatcher(Options{Bits: -1}, hexWidth); err == nil {
+		t.Error("expected an error for negative Bits")
+	}
+	if _, err := buildMatcher(Options{Bits: hexWidth*4 + 1}, hexWidth); err == nil {
+		t.Error("expected an error for Bits beyond the digest's bit width")
+	}
+	if _, err := buildMatcher(Options{Bits: hexWidth * 4}, hexWidth); err != nil {
+		t.Errorf("Bits at the maximum valid value should be accepted: %v", err)
+	}
+}
+
+func TestBuildMatcherReturnsWorkingMatcher(t *testing.T) {
+	const hexWidth = sha1.Size * 2
+	m, err := buildMatcher(Options{Prefix: "BF"}, hexWidth)
+	if err != nil {
+		t.Fatalf("buildMatcher: %v", err)
+	}
+	if !m.Match(sum(0xbf, 0x01)) {
+		t.Error("expected the built Prefix matcher to match")
+	}
+}
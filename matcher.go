@@ -0,0 +1,93 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"math/bits"
+	"regexp"
+	"strings"
+)
+
+// Matcher decides whether a candidate commit's digest is an
+// acceptable brute-force result.
+type Matcher interface {
+	// Match reports whether sum — the raw digest bytes produced by
+	// the configured hash, with spare capacity for hex-encoding —
+	// satisfies the matcher. Implementations that only need the raw
+	// bytes (like the bit-count matcher) should never call
+	// hexInPlace, short-circuiting the common case where a textual
+	// match fails early.
+	Match(sum []byte) bool
+}
+
+type prefixMatcher []byte
+
+func (m prefixMatcher) Match(sum []byte) bool { return bytes.HasPrefix(hexInPlace(sum), m) }
+
+// PrefixMatcher returns a Matcher requiring the hash's hex encoding to
+// start with prefix (matched case-insensitively).
+func PrefixMatcher(prefix string) Matcher { return prefixMatcher(strings.ToLower(prefix)) }
+
+type suffixMatcher []byte
+
+func (m suffixMatcher) Match(sum []byte) bool { return bytes.HasSuffix(hexInPlace(sum), m) }
+
+// SuffixMatcher returns a Matcher requiring the hash's hex encoding to
+// end with suffix (matched case-insensitively).
+func SuffixMatcher(suffix string) Matcher { return suffixMatcher(strings.ToLower(suffix)) }
+
+type containsMatcher []byte
+
+func (m containsMatcher) Match(sum []byte) bool { return bytes.Contains(hexInPlace(sum), m) }
+
+// ContainsMatcher returns a Matcher requiring the hash's hex encoding
+// to contain substr (matched case-insensitively) at any position.
+func ContainsMatcher(substr string) Matcher { return containsMatcher(strings.ToLower(substr)) }
+
+type regexMatcher struct{ rx *regexp.Regexp }
+
+func (m regexMatcher) Match(sum []byte) bool { return m.rx.Match(hexInPlace(sum)) }
+
+// RegexMatcher returns a Matcher requiring the hash's hex encoding to
+// match rx.
+func RegexMatcher(rx *regexp.Regexp) Matcher { return regexMatcher{rx} }
+
+type bitsMatcher int
+
+func (m bitsMatcher) Match(sum []byte) bool { return leadingZeroBits(sum) >= int(m) }
+
+// BitsMatcher returns a Matcher requiring at least n leading zero bits
+// in the raw digest, the same notion of difficulty used by
+// proof-of-work schemes. It never hex-encodes the digest.
+func BitsMatcher(n int) Matcher { return bitsMatcher(n) }
+
+// leadingZeroBits returns the number of leading zero bits in sum.
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// validHexRx matches a (possibly empty) string of hex digits, used to
+// validate the Prefix/Suffix/Contains matcher targets.
+var validHexRx = regexp.MustCompile(`^[0-9a-fA-F]*$`)
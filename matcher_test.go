@@ -0,0 +1,99 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"regexp"
+	"testing"
+)
+
+// sum returns b as a digest slice with the spare capacity Match
+// implementations that call hexInPlace require.
+func sum(b ...byte) []byte {
+	s := make([]byte, len(b), len(b)*2)
+	copy(s, b)
+	return s
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		sum  []byte
+		want int
+	}{
+		{[]byte{0xff}, 0},
+		{[]byte{0x7f}, 1},
+		{[]byte{0x00, 0xff}, 8},
+		{[]byte{0x00, 0x0f}, 12},
+		{[]byte{0x00, 0x00}, 16},
+		{[]byte{0x01}, 7},
+	}
+	for _, tt := range tests {
+		if got := leadingZeroBits(tt.sum); got != tt.want {
+			t.Errorf("leadingZeroBits(%08b) = %d; want %d", tt.sum, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixMatcher(t *testing.T) {
+	m := PrefixMatcher("BF")
+	if !m.Match(sum(0xbf, 0x01)) {
+		t.Error("expected match on case-insensitive prefix")
+	}
+	if m.Match(sum(0xbe, 0x01)) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestSuffixMatcher(t *testing.T) {
+	m := SuffixMatcher("ab")
+	if !m.Match(sum(0x01, 0xab)) {
+		t.Error("expected match on suffix")
+	}
+	if m.Match(sum(0x01, 0xac)) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestContainsMatcher(t *testing.T) {
+	m := ContainsMatcher("cd")
+	if !m.Match(sum(0x01, 0xcd, 0x02)) {
+		t.Error("expected match on contained substring")
+	}
+	if m.Match(sum(0x01, 0x02)) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestRegexMatcher(t *testing.T) {
+	m := RegexMatcher(regexp.MustCompile(`^b[ae]ef`))
+	if !m.Match(sum(0xba, 0xef)) {
+		t.Error("expected regex match")
+	}
+	if m.Match(sum(0xca, 0xfe)) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestBitsMatcher(t *testing.T) {
+	m := BitsMatcher(9)
+	if !m.Match([]byte{0x00, 0x01}) {
+		t.Error("expected match with 15 leading zero bits >= 9")
+	}
+	if m.Match([]byte{0x01, 0x00}) {
+		t.Error("unexpected match with only 7 leading zero bits")
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"encoding"
+	"hash"
+)
+
+// hashBlockSize is the block size, in bytes, of both SHA-1 and
+// SHA-256, the only hashes gitbrute supports.
+const hashBlockSize = 64
+
+// snapshotPrefix absorbs the whole 64-byte blocks of blob strictly
+// before the byte offset mutableStart into h, then marshals h's
+// internal state via encoding.BinaryMarshaler. It returns the
+// marshaled state and tailStart, the offset (a multiple of
+// hashBlockSize) where the unabsorbed remainder of blob begins. ok is
+// false if h doesn't support the marshaling interfaces, in which case
+// h is left untouched and callers should fall back to hashing blob in
+// full on every try.
+func snapshotPrefix(h hash.Hash, blob []byte, mutableStart int) (snapshot []byte, tailStart int, ok bool) {
+	bm, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, 0, false
+	}
+	if _, ok := h.(encoding.BinaryUnmarshaler); !ok {
+		return nil, 0, false
+	}
+	tailStart = (mutableStart / hashBlockSize) * hashBlockSize
+	h.Write(blob[:tailStart])
+	snapshot, err := bm.MarshalBinary()
+	if err != nil {
+		return nil, 0, false
+	}
+	return snapshot, tailStart, true
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding"
+	"hash"
+	"testing"
+)
+
+func testSnapshotPrefix(t *testing.T, newHash func() hash.Hash) {
+	blob := bytes.Repeat([]byte("0123456789abcdef"), 20) // 320 bytes, several blocks
+
+	for _, mutableStart := range []int{0, 1, 63, 64, 65, 128, len(blob)} {
+		h := newHash()
+		snapshot, tailStart, ok := snapshotPrefix(h, blob, mutableStart)
+		if !ok {
+			t.Fatalf("snapshotPrefix: hash %T doesn't support binary (un)marshaling", h)
+		}
+		bu := h.(encoding.BinaryUnmarshaler)
+		if err := bu.UnmarshalBinary(snapshot); err != nil {
+			t.Fatalf("mutableStart=%d: UnmarshalBinary: %v", mutableStart, err)
+		}
+		h.Write(blob[tailStart:])
+		got := h.Sum(nil)
+
+		full := newHash()
+		full.Write(blob)
+		want := full.Sum(nil)
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("mutableStart=%d: snapshot-resumed sum = %x; want %x", mutableStart, got, want)
+		}
+	}
+}
+
+func TestSnapshotPrefixSHA1(t *testing.T) {
+	testSnapshotPrefix(t, sha1.New)
+}
+
+func TestSnapshotPrefixSHA256(t *testing.T) {
+	testSnapshotPrefix(t, sha256.New)
+}
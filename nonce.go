@@ -0,0 +1,87 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// DefaultNonceAlphabet is the set of characters used for the message
+// nonce trailer when Options.NonceAlphabet is empty: the base64url
+// alphabet, which is safe to put in a commit message trailer verbatim.
+const DefaultNonceAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// DefaultNonceLen is the width of the nonce trailer's value when
+// Options.NonceLen is zero.
+const DefaultNonceLen = 12
+
+// nonceTrailerRx matches a "Nonce: <value>" trailer line.
+var nonceTrailerRx = regexp.MustCompile(`(?m)^Nonce: (.*)$`)
+
+// injectNonceTrailer returns a copy of obj with any existing Nonce
+// trailer line removed and a fresh one of the given width appended to
+// the message, filled with alphabet's first byte as a placeholder. It
+// also returns the byte offset of the trailer's value within the
+// returned slice.
+func injectNonceTrailer(obj []byte, alphabet string, width int) (out []byte, valueOffset int) {
+	stripRx := regexp.MustCompile(`(?m)^Nonce: .*\n?`)
+	stripped := stripRx.ReplaceAll(obj, nil)
+	if !bytes.HasSuffix(stripped, []byte("\n")) {
+		stripped = append(stripped, '\n')
+	}
+	out = append(stripped, "Nonce: "...)
+	valueOffset = len(out)
+	out = append(out, bytes.Repeat([]byte{alphabet[0]}, width)...)
+	out = append(out, '\n')
+	return out, valueOffset
+}
+
+// findNonceTrailer locates the "Nonce: <value>" trailer within blob
+// (as injected by injectNonceTrailer) and returns the byte range of
+// its value.
+func findNonceTrailer(blob []byte) (offset, width int, ok bool) {
+	m := nonceTrailerRx.FindSubmatchIndex(blob)
+	if m == nil {
+		return 0, 0, false
+	}
+	return m[2], m[3] - m[2], true
+}
+
+// nonceString renders n as a fixed-width string of length width over
+// alphabet, treating n as a base-len(alphabet) number.
+func nonceString(alphabet string, width int, n uint64) string {
+	base := uint64(len(alphabet))
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = alphabet[n%base]
+		n /= base
+	}
+	return string(buf)
+}
+
+// rewriteNonce returns a copy of content with its Nonce trailer value
+// set to value.
+func rewriteNonce(content []byte, value string) []byte {
+	out := append([]byte(nil), content...)
+	offset, width, ok := findNonceTrailer(out)
+	if !ok {
+		panic("gitbrute: rewriteNonce: no Nonce trailer found")
+	}
+	copy(out[offset:offset+width], value)
+	return out
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNonceString(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		width    int
+		n        uint64
+		want     string
+	}{
+		{"01", 4, 0, "0000"},
+		{"01", 4, 5, "0101"},
+		{DefaultNonceAlphabet, 1, 1, "B"},
+	}
+	for _, tt := range tests {
+		if got := nonceString(tt.alphabet, tt.width, tt.n); got != tt.want {
+			t.Errorf("nonceString(%q, %d, %d) = %q; want %q", tt.alphabet, tt.width, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestInjectAndFindNonceTrailer(t *testing.T) {
+	obj := []byte("commit message\n\nbody text\n")
+	out, valueOffset := injectNonceTrailer(obj, DefaultNonceAlphabet, 8)
+
+	offset, width, ok := findNonceTrailer(out)
+	if !ok {
+		t.Fatal("findNonceTrailer didn't find the trailer we just injected")
+	}
+	if offset != valueOffset {
+		t.Errorf("findNonceTrailer offset = %d; want %d", offset, valueOffset)
+	}
+	if width != 8 {
+		t.Errorf("findNonceTrailer width = %d; want 8", width)
+	}
+	if !bytes.Equal(out[offset:offset+width], bytes.Repeat([]byte{DefaultNonceAlphabet[0]}, 8)) {
+		t.Errorf("placeholder value = %q; want all %q", out[offset:offset+width], DefaultNonceAlphabet[0])
+	}
+}
+
+func TestInjectNonceTrailerReplacesExisting(t *testing.T) {
+	obj := []byte("commit message\n\nbody text\nNonce: oldvalue\n")
+	out, _ := injectNonceTrailer(obj, DefaultNonceAlphabet, 4)
+	if bytes.Count(out, []byte("Nonce: ")) != 1 {
+		t.Errorf("expected exactly one Nonce trailer, got %q", out)
+	}
+	if bytes.Contains(out, []byte("oldvalue")) {
+		t.Errorf("old trailer value survived: %q", out)
+	}
+}
+
+func TestRewriteNonce(t *testing.T) {
+	obj := []byte("commit message\n\nbody text\n")
+	out, _ := injectNonceTrailer(obj, DefaultNonceAlphabet, 6)
+	rewritten := rewriteNonce(out, "abcdef")
+	offset, width, ok := findNonceTrailer(rewritten)
+	if !ok {
+		t.Fatal("findNonceTrailer failed after rewriteNonce")
+	}
+	if got := string(rewritten[offset : offset+width]); got != "abcdef" {
+		t.Errorf("rewritten nonce value = %q; want %q", got, "abcdef")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Repo is a handle to a local git repository whose HEAD commit
+// gitbrute can re-forge in place, entirely in-process (no git binary
+// required).
+type Repo struct {
+	repo *git.Repository
+}
+
+// Open opens the git repository containing path, searching parent
+// directories the same way the git command-line tool does.
+func Open(path string) (*Repo, error) {
+	r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("gitbrute: opening repo: %w", err)
+	}
+	return &Repo{repo: r}, nil
+}
+
+// HeadHash returns the hash of the repository's current HEAD commit.
+func (r *Repo) HeadHash() (plumbing.Hash, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: resolving HEAD: %w", err)
+	}
+	return ref.Hash(), nil
+}
+
+// ObjectFormat returns the repository's extensions.objectFormat config
+// value ("sha1" or "sha256"), or the empty string if it's unset
+// (meaning sha1).
+func (r *Repo) ObjectFormat() string {
+	cfg, err := r.repo.Config()
+	if err != nil {
+		return ""
+	}
+	return cfg.Raw.Section("extensions").Option("objectFormat")
+}
+
+// HeadCommitObject returns the hash and raw object content (the
+// "commit <len>\x00" header's payload, i.e. what `git cat-file -p`
+// prints) of the repository's HEAD commit.
+func (r *Repo) HeadCommitObject() (hash plumbing.Hash, content []byte, err error) {
+	hash, err = r.HeadHash()
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+	eo, err := r.repo.Storer.EncodedObject(plumbing.CommitObject, hash)
+	if err != nil {
+		return plumbing.ZeroHash, nil, fmt.Errorf("gitbrute: loading commit %s: %w", hash, err)
+	}
+	rd, err := eo.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, nil, fmt.Errorf("gitbrute: reading commit %s: %w", hash, err)
+	}
+	defer rd.Close()
+	content, err = io.ReadAll(rd)
+	if err != nil {
+		return plumbing.ZeroHash, nil, fmt.Errorf("gitbrute: reading commit %s: %w", hash, err)
+	}
+	return hash, content, nil
+}
+
+// Amend stores content as a new commit object and repoints the
+// current branch (or HEAD itself, if detached) at it, the in-process
+// equivalent of `git commit --amend`. It returns the new commit's
+// hash.
+func (r *Repo) Amend(content []byte) (plumbing.Hash, error) {
+	eo := r.repo.Storer.NewEncodedObject()
+	eo.SetType(plumbing.CommitObject)
+	w, err := eo.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: writing commit: %w", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: writing commit: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: writing commit: %w", err)
+	}
+	newHash, err := r.repo.Storer.SetEncodedObject(eo)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: storing commit: %w", err)
+	}
+
+	head, err := r.repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: resolving HEAD: %w", err)
+	}
+	target := plumbing.HEAD
+	if head.Type() == plumbing.SymbolicReference {
+		target = head.Target()
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(target, newHash)); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("gitbrute: updating %s: %w", target, err)
+	}
+	return newHash, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// initTestRepo creates a throwaway repository in a tempdir with a
+// single commit, and returns it opened both as a *Repo (for the
+// functions under test) and as the underlying *git.Repository (for
+// setup not exposed by Repo).
+func initTestRepo(t *testing.T) (*Repo, *git.Repository, string) {
+	t.Helper()
+	dir := t.TempDir()
+	gr, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := gr.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree: %v", err)
+	}
+	if _, err := wt.Add("f.txt"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(0, 0).UTC()}
+	if _, err := wt.Commit("initial commit\n", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	r, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return r, gr, dir
+}
+
+func TestOpenAndHeadHash(t *testing.T) {
+	r, gr, _ := initTestRepo(t)
+	got, err := r.HeadHash()
+	if err != nil {
+		t.Fatalf("HeadHash: %v", err)
+	}
+	want, err := gr.Head()
+	if err != nil {
+		t.Fatalf("(*git.Repository).Head: %v", err)
+	}
+	if got != want.Hash() {
+		t.Errorf("HeadHash = %s; want %s", got, want.Hash())
+	}
+}
+
+func TestOpenFromSubdir(t *testing.T) {
+	_, _, dir := initTestRepo(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(sub); err != nil {
+		t.Errorf("Open(%q) with DetectDotGit should find the repo at %q: %v", sub, dir, err)
+	}
+}
+
+func TestObjectFormatDefaultsEmpty(t *testing.T) {
+	r, _, _ := initTestRepo(t)
+	if got := r.ObjectFormat(); got != "" {
+		t.Errorf("ObjectFormat = %q; want \"\" for a repo with no extensions.objectFormat set", got)
+	}
+}
+
+func TestHeadCommitObjectAndAmend(t *testing.T) {
+	r, _, _ := initTestRepo(t)
+	hash, content, err := r.HeadCommitObject()
+	if err != nil {
+		t.Fatalf("HeadCommitObject: %v", err)
+	}
+	if !bytes.Contains(content, []byte("initial commit")) {
+		t.Fatalf("HeadCommitObject content missing message: %q", content)
+	}
+
+	amended := bytes.Replace(content, []byte("initial commit"), []byte("amended commit"), 1)
+	newHash, err := r.Amend(amended)
+	if err != nil {
+		t.Fatalf("Amend: %v", err)
+	}
+	if newHash == hash {
+		t.Fatal("Amend produced the same hash as before, despite changing content")
+	}
+
+	head, err := r.HeadHash()
+	if err != nil {
+		t.Fatalf("HeadHash after Amend: %v", err)
+	}
+	if head != newHash {
+		t.Errorf("HeadHash after Amend = %s; want %s", head, newHash)
+	}
+
+	_, content2, err := r.HeadCommitObject()
+	if err != nil {
+		t.Fatalf("HeadCommitObject after Amend: %v", err)
+	}
+	if !bytes.Contains(content2, []byte("amended commit")) {
+		t.Errorf("HeadCommitObject after Amend doesn't reflect the amendment: %q", content2)
+	}
+}
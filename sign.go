@@ -0,0 +1,184 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+// gpgsigHeaderPrefix is the commit object header line that carries a
+// detached signature over the rest of the commit.
+const gpgsigHeaderPrefix = "gpgsig "
+
+// stripGPGSig returns a copy of content with its gpgsig header (and
+// any RFC 2822-style continuation lines, each starting with a single
+// space) removed. found reports whether one was present.
+func stripGPGSig(content []byte) (stripped []byte, found bool) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var out [][]byte
+	for i := 0; i < len(lines); i++ {
+		if bytes.HasPrefix(lines[i], []byte(gpgsigHeaderPrefix)) {
+			found = true
+			i++
+			for i < len(lines) && len(lines[i]) > 0 && lines[i][0] == ' ' {
+				i++
+			}
+			i--
+			continue
+		}
+		out = append(out, lines[i])
+	}
+	return bytes.Join(out, nil), found
+}
+
+// insertGPGSig returns a copy of content (which must not already
+// have a gpgsig header) with folded, a gpgsig header as produced by
+// foldGPGSig, inserted as the last header line before the blank line
+// that separates headers from the commit message.
+func insertGPGSig(content, folded []byte) []byte {
+	i := bytes.Index(content, []byte("\n\n"))
+	if i < 0 {
+		return append(append([]byte(nil), content...), folded...)
+	}
+	out := make([]byte, 0, len(content)+len(folded))
+	out = append(out, content[:i+1]...)
+	out = append(out, folded...)
+	out = append(out, content[i+1:]...)
+	return out
+}
+
+// foldGPGSig renders an ASCII-armored signature as a gpgsig header
+// block, folding each line per git's convention: the first line is
+// prefixed with "gpgsig ", and each subsequent line with a single
+// space.
+func foldGPGSig(armored []byte) []byte {
+	lines := bytes.Split(bytes.TrimRight(armored, "\n"), []byte("\n"))
+	var b bytes.Buffer
+	for i, l := range lines {
+		if i == 0 {
+			b.WriteString(gpgsigHeaderPrefix)
+		} else {
+			b.WriteByte(' ')
+		}
+		b.Write(l)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// signingConfig returns r's configured user.signingkey, gpg.format,
+// and commit.gpgsign, resolved the same way git itself would: local
+// config takes precedence over global, which takes precedence over
+// system.
+func (r *Repo) signingConfig() (key, format string, gpgsign bool, err error) {
+	cfg, err := r.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return "", "", false, fmt.Errorf("gitbrute: reading config: %w", err)
+	}
+	user := cfg.Raw.Section("user")
+	gpg := cfg.Raw.Section("gpg")
+	commit := cfg.Raw.Section("commit")
+	return user.Option("signingkey"), gpg.Option("format"), gitBool(commit.Option("gpgsign")), nil
+}
+
+// gitBool parses s using git's boolean config grammar: "true", "yes",
+// "on", and "1" are true (matched case-insensitively); anything else,
+// including an unset value, is false.
+func gitBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+// needsSignature reports whether a re-forged HEAD must carry a valid
+// signature: either it had one already (hadSig), or the repository is
+// configured to always sign commits.
+func (r *Repo) needsSignature(hadSig bool) (bool, error) {
+	if hadSig {
+		return true, nil
+	}
+	_, _, gpgsign, err := r.signingConfig()
+	if err != nil {
+		return false, err
+	}
+	return gpgsign, nil
+}
+
+// sign signs content (a gpgsig-free commit object body) using r's
+// configured signing key and gpg.format, and returns a copy of
+// content with the resulting signature inserted as a gpgsig header.
+// It fails loudly, rather than silently producing an unsigned commit,
+// if no signing key is configured.
+func (r *Repo) sign(content []byte) ([]byte, error) {
+	key, format, _, err := r.signingConfig()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("gitbrute: HEAD must be re-signed (it was signed, or commit.gpgsign is set) but no user.signingkey is configured")
+	}
+
+	var armored []byte
+	switch format {
+	case "", "openpgp":
+		armored, err = gpgSign(content, key)
+	case "ssh":
+		armored, err = sshSign(content, key)
+	default:
+		return nil, fmt.Errorf("gitbrute: unsupported gpg.format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gitbrute: re-signing commit: %w", err)
+	}
+	return insertGPGSig(content, foldGPGSig(armored)), nil
+}
+
+// gpgSign produces a detached, ASCII-armored OpenPGP signature over
+// content using gpg and the given local user/key.
+func gpgSign(content []byte, key string) ([]byte, error) {
+	cmd := exec.Command("gpg", "--detach-sign", "--armor", "--local-user", key)
+	cmd.Stdin = bytes.NewReader(content)
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg: %v: %s", err, bytes.TrimSpace(errOut.Bytes()))
+	}
+	return out.Bytes(), nil
+}
+
+// sshSign produces a detached SSH signature over content using
+// ssh-keygen, in the "git" signing namespace git itself uses.
+func sshSign(content []byte, keyFile string) ([]byte, error) {
+	cmd := exec.Command("ssh-keygen", "-Y", "sign", "-n", "git", "-f", keyFile)
+	cmd.Stdin = bytes.NewReader(content)
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ssh-keygen: %v: %s", err, bytes.TrimSpace(errOut.Bytes()))
+	}
+	return out.Bytes(), nil
+}
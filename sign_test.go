@@ -0,0 +1,92 @@
+/*
+Copyright 2014 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitbrute
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFoldGPGSig(t *testing.T) {
+	armored := []byte("-----BEGIN PGP SIGNATURE-----\n\nabcd\nefgh\n-----END PGP SIGNATURE-----\n")
+	got := foldGPGSig(armored)
+	want := "gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+		" \n" +
+		" abcd\n" +
+		" efgh\n" +
+		" -----END PGP SIGNATURE-----\n"
+	if string(got) != want {
+		t.Errorf("foldGPGSig = %q; want %q", got, want)
+	}
+}
+
+func TestInsertAndStripGPGSig(t *testing.T) {
+	content := []byte("tree abc\nauthor a <a@example.com> 1 +0000\n\ncommit message\n")
+	folded := foldGPGSig([]byte("-----BEGIN PGP SIGNATURE-----\nabcd\n-----END PGP SIGNATURE-----\n"))
+
+	signed := insertGPGSig(content, folded)
+	if !bytes.Contains(signed, []byte("gpgsig -----BEGIN PGP SIGNATURE-----\n")) {
+		t.Fatalf("insertGPGSig didn't insert the folded header: %q", signed)
+	}
+	if !bytes.HasSuffix(signed, []byte("\ncommit message\n")) {
+		t.Errorf("insertGPGSig corrupted the message body: %q", signed)
+	}
+
+	stripped, found := stripGPGSig(signed)
+	if !found {
+		t.Fatal("stripGPGSig didn't find the header it was given")
+	}
+	if !bytes.Equal(stripped, content) {
+		t.Errorf("strip(insert(content)) = %q; want original %q", stripped, content)
+	}
+}
+
+func TestStripGPGSigNotPresent(t *testing.T) {
+	content := []byte("tree abc\nauthor a <a@example.com> 1 +0000\n\ncommit message\n")
+	stripped, found := stripGPGSig(content)
+	if found {
+		t.Error("found = true for content with no gpgsig header")
+	}
+	if !bytes.Equal(stripped, content) {
+		t.Errorf("stripGPGSig modified content with no header: %q", stripped)
+	}
+}
+
+func TestGitBool(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"true", true},
+		{"True", true},
+		{"yes", true},
+		{"YES", true},
+		{"on", true},
+		{"1", true},
+		{"false", false},
+		{"no", false},
+		{"off", false},
+		{"0", false},
+		{"", false},
+		{"garbage", false},
+	}
+	for _, tt := range tests {
+		if got := gitBool(tt.s); got != tt.want {
+			t.Errorf("gitBool(%q) = %v; want %v", tt.s, got, tt.want)
+		}
+	}
+}